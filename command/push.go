@@ -4,23 +4,31 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/atlas-go/archive"
-	"github.com/hashicorp/atlas-go/v1"
 	"github.com/mitchellh/packer/packer"
 )
 
 // archiveTemplateEntry is the name the template always takes within the slug.
 const archiveTemplateEntry = ".packer-template"
 
+// defaultPushBackend is the backend used when a template's push section
+// doesn't set "type", preserving the historical behavior of "packer
+// push" talking to Atlas.
+const defaultPushBackend = "atlas"
+
 type PushCommand struct {
 	Meta
 
-	client *atlas.Client
+	// progressStart is when the current upload began, used to compute
+	// a bytes/sec rate for uploadProgress.
+	progressStart time.Time
 
 	// For tests:
 	uploadFn pushUploadFn
@@ -29,17 +37,21 @@ type PushCommand struct {
 // pushUploadFn is the callback type used for tests to stub out the uploading
 // logic of the push command.
 type pushUploadFn func(
-	io.Reader, *uploadOpts) (<-chan struct{}, <-chan error, error)
+	io.Reader, int64, *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error)
 
 func (c *PushCommand) Run(args []string) int {
 	var token string
 	var message string
+	var output string
+	var dryRun bool
 
 	f := flag.NewFlagSet("push", flag.ContinueOnError)
 	f.Usage = func() { c.Ui.Error(c.Help()) }
 	f.StringVar(&token, "token", "", "token")
 	f.StringVar(&message, "m", "", "message")
 	f.StringVar(&message, "message", "", "message")
+	f.StringVar(&output, "output", "", "output")
+	f.BoolVar(&dryRun, "dry-run", false, "dry run")
 	if err := f.Parse(args); err != nil {
 		return 1
 	}
@@ -70,19 +82,17 @@ func (c *PushCommand) Run(args []string) int {
 		token = tpl.Push.Token
 	}
 
-	// Build our client
-	defer func() { c.client = nil }()
-	c.client = atlas.DefaultClient()
-	if tpl.Push.Address != "" {
-		c.client, err = atlas.NewClient(tpl.Push.Address)
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf(
-				"Error setting up API client: %s", err))
-			return 1
-		}
+	// Determine the local output path, if any. When set, we skip
+	// uploading entirely and just write the archive to disk.
+	if output == "" {
+		output = tpl.Push.Output
 	}
-	if token != "" {
-		c.client.Token = token
+
+	// Determine the backend. Templates written before pluggable backends
+	// existed don't set this, so fall back to Atlas.
+	backendType := tpl.Push.Type
+	if backendType == "" {
+		backendType = defaultPushBackend
 	}
 
 	// Build the archiving options
@@ -122,6 +132,18 @@ func (c *PushCommand) Run(args []string) int {
 		}
 	}
 
+	// Validate the template directory and include/exclude patterns
+	// before we go to the trouble of archiving anything.
+	templateDir := filepath.Dir(args[0])
+	if err := validateBaseDir(templateDir, tpl.Push.BaseDir, path); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := validatePushPatterns(path, &opts); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Find the Atlas post-processors, if possible
 	var atlasPPs []packer.RawPostProcessorConfig
 	for _, list := range tpl.PostProcessors {
@@ -133,11 +155,14 @@ func (c *PushCommand) Run(args []string) int {
 	}
 
 	// Build the upload options
-	var uploadOpts uploadOpts
+	var uploadOpts packer.PushUploadOpts
+	uploadOpts.URL = tpl.Push.Address
+	uploadOpts.Token = token
 	uploadOpts.Slug = tpl.Push.Name
-	uploadOpts.Builds = make(map[string]*uploadBuildInfo)
+	uploadOpts.Progress = c.uploadProgress
+	uploadOpts.Builds = make(map[string]*packer.PushUploadBuildInfo)
 	for _, b := range tpl.Builders {
-		info := &uploadBuildInfo{Type: b.Type}
+		info := &packer.PushUploadBuildInfo{Type: b.Type}
 
 		// Determine if we're artifacting this build
 		for _, pp := range atlasPPs {
@@ -177,6 +202,27 @@ func (c *PushCommand) Run(args []string) int {
 				"Builds: %s\n\n", strings.Join(badBuilds, ", ")))
 	}
 
+	// In dry-run mode we stop here: print a manifest of what would be
+	// uploaded and never touch the archive or any backend.
+	if dryRun {
+		if opts.VCS {
+			c.Ui.Error(
+				"Warning! push.vcs is set, so the real archive's file list comes\n" +
+					"from your version control system rather than a directory walk.\n" +
+					"This manifest is a best-effort approximation and may not match\n" +
+					"exactly.\n")
+		}
+
+		files, err := manifestFiles(path, &opts)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error building manifest: %s", err))
+			return 1
+		}
+
+		c.printManifest(tpl, path, &uploadOpts, files)
+		return 0
+	}
+
 	// Start the archiving process
 	r, err := archive.CreateArchive(path, &opts)
 	if err != nil {
@@ -185,8 +231,27 @@ func (c *PushCommand) Run(args []string) int {
 	}
 	defer r.Close()
 
+	if err := validateMaxSize(r.Size, tpl.Push.MaxSize); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// If an output path was given, just write the archive there and
+	// skip uploading entirely. This lets users inspect exactly what
+	// would be pushed, or ship the slug through their own CI/CD.
+	if output != "" {
+		if err := writeArchive(output, r); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing archive: %s", err))
+			return 1
+		}
+
+		c.Ui.Output(fmt.Sprintf("Archive written to '%s'", output))
+		return 0
+	}
+
 	// Start the upload process
-	doneCh, uploadErrCh, err := c.upload(r, &uploadOpts)
+	c.progressStart = time.Now()
+	doneCh, uploadErrCh, err := c.upload(backendType, r, r.Size, &uploadOpts)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error starting upload: %s", err))
 		return 1
@@ -230,11 +295,22 @@ Usage: packer push [options] TEMPLATE
   include) may be specified in the "push" section of the Packer template. Please
   see the online documentation for more information about these configurables.
 
+  By default the archive is uploaded to Atlas. Set "type" in the template's
+  push section to "s3", "gcs", "http", or "file" to upload it somewhere else
+  instead.
+
 Options:
 
+  -dry-run                 Validate the template and print a manifest of what
+                           would be uploaded without archiving or uploading
+                           anything
+
   -m, -message=<detail>    A message to identify the purpose or changes in this
                            Packer template much like a VCS commit message
 
+  -output=<path>           Write the archive to this path instead of uploading
+                           it anywhere
+
   -token=<token>           The access token to use to when uploading
 `
 
@@ -245,68 +321,54 @@ func (*PushCommand) Synopsis() string {
 	return "push a template and supporting files to a Packer build service"
 }
 
-func (c *PushCommand) upload(
-	r *archive.Archive, opts *uploadOpts) (<-chan struct{}, <-chan error, error) {
-	if c.uploadFn != nil {
-		return c.uploadFn(r, opts)
-	}
-
-	// Separate the slug into the user and name components
-	user, name, err := atlas.ParseSlug(opts.Slug)
-	if err != nil {
-		return nil, nil, fmt.Errorf("upload: %s", err)
-	}
-
-	// Get the build configuration
-	bc, err := c.client.BuildConfig(user, name)
+// writeArchive copies r, the tar.gz produced by archive.CreateArchive,
+// into a tempfile next to the final destination and then renames it
+// into place so a reader never sees a partially-written archive.
+func writeArchive(dest string, r io.Reader) error {
+	f, err := ioutil.TempFile(filepath.Dir(dest), ".packer-push-")
 	if err != nil {
-		if err == atlas.ErrNotFound {
-			// Build configuration doesn't exist, attempt to create it
-			bc, err = c.client.CreateBuildConfig(user, name)
-		}
-
-		if err != nil {
-			return nil, nil, fmt.Errorf("upload: %s", err)
-		}
+		return err
 	}
+	defer os.Remove(f.Name())
 
-	// Build the version to send up
-	version := atlas.BuildConfigVersion{
-		User:   bc.User,
-		Name:   bc.Name,
-		Builds: make([]atlas.BuildConfigBuild, 0, len(opts.Builds)),
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
 	}
-	for name, info := range opts.Builds {
-		version.Builds = append(version.Builds, atlas.BuildConfigBuild{
-			Name:     name,
-			Type:     info.Type,
-			Artifact: info.Artifact,
-		})
+	if err := f.Close(); err != nil {
+		return err
 	}
 
-	// Start the upload
-	doneCh, errCh := make(chan struct{}), make(chan error)
-	go func() {
-		err := c.client.UploadBuildConfigVersion(&version, opts.Metadata, r, r.Size)
-		if err != nil {
-			errCh <- err
-			return
-		}
+	return os.Rename(f.Name(), dest)
+}
 
-		close(doneCh)
-	}()
+// uploadProgress reports how much of the archive has been sent so far,
+// along with the average transfer rate since the upload started.
+func (c *PushCommand) uploadProgress(sent, total int64) {
+	elapsed := time.Since(c.progressStart).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(sent) / elapsed
+	}
 
-	return doneCh, errCh, nil
+	c.Ui.Output(fmt.Sprintf(
+		"Uploaded %d/%d bytes (%.1f KB/s)", sent, total, rate/1024))
 }
 
-type uploadOpts struct {
-	URL      string
-	Slug     string
-	Builds   map[string]*uploadBuildInfo
-	Metadata map[string]interface{}
-}
+// upload dispatches the archive to the named push backend, or to the
+// stubbed uploadFn when running under test.
+func (c *PushCommand) upload(
+	backendType string,
+	r io.Reader, size int64,
+	opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	if c.uploadFn != nil {
+		return c.uploadFn(r, size, opts)
+	}
+
+	backend, ok := packer.PushBackendFor(backendType)
+	if !ok {
+		return nil, nil, fmt.Errorf("upload: unknown push backend type %q", backendType)
+	}
 
-type uploadBuildInfo struct {
-	Type     string
-	Artifact bool
+	return backend.Upload(r, size, opts)
 }
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/atlas-go/v1"
+	"github.com/mitchellh/packer/packer"
+)
+
+func init() {
+	packer.RegisterPushBackend("atlas", func() packer.PushBackend {
+		return new(atlasPushBackend)
+	})
+}
+
+// atlasPushBackend uploads the archive to an Atlas build configuration
+// version. This is the original, and still default, behavior of
+// "packer push".
+type atlasPushBackend struct{}
+
+func (b *atlasPushBackend) Upload(
+	r io.Reader, size int64, opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	// Build our client
+	client := atlas.DefaultClient()
+	if opts.URL != "" {
+		var err error
+		client, err = atlas.NewClient(opts.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("upload: %s", err)
+		}
+	}
+	if opts.Token != "" {
+		client.Token = opts.Token
+	}
+
+	// Separate the slug into the user and name components
+	user, name, err := atlas.ParseSlug(opts.Slug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+
+	// Get the build configuration
+	bc, err := client.BuildConfig(user, name)
+	if err != nil {
+		if err == atlas.ErrNotFound {
+			// Build configuration doesn't exist, attempt to create it
+			bc, err = client.CreateBuildConfig(user, name)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("upload: %s", err)
+		}
+	}
+
+	// Build the version to send up
+	version := atlas.BuildConfigVersion{
+		User:   bc.User,
+		Name:   bc.Name,
+		Builds: make([]atlas.BuildConfigBuild, 0, len(opts.Builds)),
+	}
+	for name, info := range opts.Builds {
+		version.Builds = append(version.Builds, atlas.BuildConfigBuild{
+			Name:     name,
+			Type:     info.Type,
+			Artifact: info.Artifact,
+		})
+	}
+
+	// archive.CreateArchive only promises an io.ReadCloser, so we can't
+	// assume r supports Seek. Buffer it to a temp file once so the
+	// chunked uploader below can retry and report progress against a
+	// reader it can rewind.
+	rs, seekable := r.(io.ReadSeeker)
+	var cleanup func()
+	if !seekable {
+		buffered, err := bufferToSeekableFile(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("upload: %s", err)
+		}
+		rs = buffered
+		cleanup = func() {
+			buffered.Close()
+			os.Remove(buffered.Name())
+		}
+	}
+
+	// Start the upload, retrying and reporting progress as it goes. Every
+	// attempt re-sends the whole archive: UploadBuildConfigVersion has no
+	// partial/resumable endpoint to continue a prior attempt against, so
+	// there is no session or on-disk state to persist between retries.
+	doneCh, errCh := make(chan struct{}), make(chan error)
+	go func() {
+		if cleanup != nil {
+			defer cleanup()
+		}
+
+		err := chunkedUpload(rs, size, opts.Progress,
+			func(chunk io.Reader, chunkSize int64) error {
+				return client.UploadBuildConfigVersion(&version, opts.Metadata, chunk, chunkSize)
+			})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		close(doneCh)
+	}()
+
+	return doneCh, errCh, nil
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func init() {
+	packer.RegisterPushBackend("file", func() packer.PushBackend {
+		return new(filePushBackend)
+	})
+}
+
+// filePushBackend writes the archive to a path on the local filesystem,
+// taken from the push section's "address". It exists for users who want
+// to ship slugs through their own CI/CD rather than through Atlas.
+type filePushBackend struct{}
+
+func (b *filePushBackend) Upload(
+	r io.Reader, size int64, opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	if opts.URL == "" {
+		return nil, nil, fmt.Errorf("upload: 'address' must be set to a destination path for the file backend")
+	}
+
+	f, err := os.Create(opts.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+
+	doneCh, errCh := make(chan struct{}), make(chan error)
+	go func() {
+		defer f.Close()
+
+		if _, err := io.Copy(f, r); err != nil {
+			errCh <- fmt.Errorf("upload: %s", err)
+			return
+		}
+
+		close(doneCh)
+	}()
+
+	return doneCh, errCh, nil
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/mitchellh/packer/packer"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	packer.RegisterPushBackend("gcs", func() packer.PushBackend {
+		return new(gcsPushBackend)
+	})
+}
+
+// gcsPushBackend uploads the archive to a Google Cloud Storage object.
+// The push section's "address" is a "gs://bucket/object" URL.
+type gcsPushBackend struct{}
+
+func (b *gcsPushBackend) Upload(
+	r io.Reader, size int64, opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	bucket, object, err := parseGCSURL(opts.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+
+	doneCh, errCh := make(chan struct{}), make(chan error)
+	go func() {
+		w := client.Bucket(bucket).Object(object).NewWriter(ctx)
+		if _, err := io.Copy(w, r); err != nil {
+			errCh <- fmt.Errorf("upload: %s", err)
+			return
+		}
+		if err := w.Close(); err != nil {
+			errCh <- fmt.Errorf("upload: %s", err)
+			return
+		}
+
+		close(doneCh)
+	}()
+
+	return doneCh, errCh, nil
+}
+
+// parseGCSURL splits a "gs://bucket/object" address into its bucket and
+// object components.
+func parseGCSURL(url string) (bucket, object string, err error) {
+	const prefix = "gs://"
+	if !strings.HasPrefix(url, prefix) {
+		return "", "", fmt.Errorf("'address' must be a gs:// URL for the gcs backend")
+	}
+
+	rest := url[len(prefix):]
+	idx := strings.Index(rest, "/")
+	if idx == -1 || idx == len(rest)-1 {
+		return "", "", fmt.Errorf("'address' must include a bucket and object, e.g. gs://bucket/object")
+	}
+
+	return rest[:idx], rest[idx+1:], nil
+}
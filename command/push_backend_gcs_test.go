@@ -0,0 +1,41 @@
+package command
+
+import "testing"
+
+func TestParseGCSURL(t *testing.T) {
+	cases := []struct {
+		name      string
+		url       string
+		bucket    string
+		object    string
+		expectErr bool
+	}{
+		{"valid", "gs://my-bucket/path/to/object.tar.gz", "my-bucket", "path/to/object.tar.gz", false},
+		{"bucket only", "gs://my-bucket", "", "", true},
+		{"bucket with trailing slash", "gs://my-bucket/", "", "", true},
+		{"wrong scheme", "s3://my-bucket/object", "", "", true},
+		{"empty", "", "", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket, object, err := parseGCSURL(tc.url)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if bucket != tc.bucket {
+				t.Errorf("bucket = %q, want %q", bucket, tc.bucket)
+			}
+			if object != tc.object {
+				t.Errorf("object = %q, want %q", object, tc.object)
+			}
+		})
+	}
+}
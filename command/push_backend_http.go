@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func init() {
+	packer.RegisterPushBackend("http", func() packer.PushBackend {
+		return new(httpPushBackend)
+	})
+}
+
+// httpPushBackend PUTs the archive to an arbitrary HTTP endpoint, taken
+// from the push section's "address". This is the simplest backend and
+// works with any service that accepts a raw PUT of a file, such as a
+// signed URL from a private object store.
+type httpPushBackend struct{}
+
+func (b *httpPushBackend) Upload(
+	r io.Reader, size int64, opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	if opts.URL == "" {
+		return nil, nil, fmt.Errorf("upload: 'address' must be set to a destination URL for the http backend")
+	}
+
+	req, err := http.NewRequest("PUT", opts.URL, r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+	req.ContentLength = size
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+
+	doneCh, errCh := make(chan struct{}), make(chan error)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			errCh <- fmt.Errorf("upload: %s", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			errCh <- fmt.Errorf("upload: unexpected status code %d", resp.StatusCode)
+			return
+		}
+
+		close(doneCh)
+	}()
+
+	return doneCh, errCh, nil
+}
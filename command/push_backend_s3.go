@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/mitchellh/packer/packer"
+)
+
+func init() {
+	packer.RegisterPushBackend("s3", func() packer.PushBackend {
+		return new(s3PushBackend)
+	})
+}
+
+// s3PushBackend uploads the archive to an S3 object, for users who want
+// to publish slugs to their own bucket instead of Atlas. The push
+// section's "address" is an "s3://bucket/key" URL.
+type s3PushBackend struct{}
+
+func (b *s3PushBackend) Upload(
+	r io.Reader, size int64, opts *packer.PushUploadOpts) (<-chan struct{}, <-chan error, error) {
+	bucket, key, err := parseS3URL(opts.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload: %s", err)
+	}
+
+	uploader := s3manager.NewUploader(session.New(aws.NewConfig()))
+
+	doneCh, errCh := make(chan struct{}), make(chan error)
+	go func() {
+		// s3manager.Uploader computes its own part sizes from Body as it
+		// reads, so there is no ContentLength field to set here.
+		_, err := uploader.Upload(&s3manager.UploadInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   r,
+		})
+		if err != nil {
+			errCh <- fmt.Errorf("upload: %s", err)
+			return
+		}
+
+		close(doneCh)
+	}()
+
+	return doneCh, errCh, nil
+}
+
+// parseS3URL splits an "s3://bucket/key" address into its bucket and
+// key components.
+func parseS3URL(url string) (bucket, key string, err error) {
+	const prefix = "s3://"
+	if !strings.HasPrefix(url, prefix) {
+		return "", "", fmt.Errorf("'address' must be an s3:// URL for the s3 backend")
+	}
+
+	rest := url[len(prefix):]
+	idx := strings.Index(rest, "/")
+	if idx == -1 || idx == len(rest)-1 {
+		return "", "", fmt.Errorf("'address' must include a bucket and key, e.g. s3://bucket/key")
+	}
+
+	return rest[:idx], rest[idx+1:], nil
+}
@@ -0,0 +1,41 @@
+package command
+
+import "testing"
+
+func TestParseS3URL(t *testing.T) {
+	cases := []struct {
+		name      string
+		url       string
+		bucket    string
+		key       string
+		expectErr bool
+	}{
+		{"valid", "s3://my-bucket/path/to/key.tar.gz", "my-bucket", "path/to/key.tar.gz", false},
+		{"bucket only", "s3://my-bucket", "", "", true},
+		{"bucket with trailing slash", "s3://my-bucket/", "", "", true},
+		{"wrong scheme", "gs://my-bucket/key", "", "", true},
+		{"empty", "", "", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket, key, err := parseS3URL(tc.url)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if bucket != tc.bucket {
+				t.Errorf("bucket = %q, want %q", bucket, tc.bucket)
+			}
+			if key != tc.key {
+				t.Errorf("key = %q, want %q", key, tc.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+// uploadChunkSize is the granularity at which a retried upload reports
+// progress. It does not change how many network calls are made: the
+// Atlas build-config-version API has no partial/resumable endpoint, so
+// every attempt streams the whole archive in one request. We still
+// track progress every uploadChunkSize bytes so large uploads don't
+// look hung.
+const uploadChunkSize = 5 * 1024 * 1024 // 5 MiB
+
+// maxUploadAttempts is the total number of upload attempts, the first
+// one included, before giving up; attempts after the first follow a
+// transient failure and wait with exponential backoff.
+const maxUploadAttempts = 5
+
+// bufferToSeekableFile copies r into a temp file and returns it
+// rewound to the start. archive.CreateArchive only promises an
+// io.ReadCloser, so backends that need to seek (to retry an upload)
+// can't rely on the archive itself supporting Seek; buffering it once
+// to disk gives them that guarantee. The caller is responsible for
+// closing and removing the returned file.
+func bufferToSeekableFile(r io.Reader) (*os.File, error) {
+	f, err := ioutil.TempFile("", "packer-push-archive-")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// chunkedUploadSend performs a single upload attempt, sending exactly
+// size bytes read from r.
+type chunkedUploadSend func(r io.Reader, size int64) error
+
+// chunkedUpload retries send against a seekable reader, reporting
+// progress every uploadChunkSize bytes as it goes. Because the backing
+// API has no way to accept a partial archive, every attempt re-sends
+// the archive from the beginning; only errors that look transient (a
+// dropped connection, a timeout) are retried; anything else - a bad
+// token, a validation error - is returned immediately since retrying it
+// would just fail the same way.
+func chunkedUpload(
+	r io.ReadSeeker, size int64,
+	progress func(sent, total int64), send chunkedUploadSend) error {
+	var lastErr error
+	for attempt := 0; attempt < maxUploadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(chunkBackoff(attempt))
+		}
+
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("retrying upload: %s", err)
+		}
+
+		tracker := &chunkProgressReader{r: r, total: size, progress: progress}
+		lastErr = send(tracker, size)
+		if lastErr == nil {
+			return nil
+		}
+		if !isTransientUploadErr(lastErr) {
+			return lastErr
+		}
+	}
+
+	return fmt.Errorf("upload failed after %d attempts: %s", maxUploadAttempts, lastErr)
+}
+
+// isTransientUploadErr reports whether err looks like a transient
+// network failure worth retrying, as opposed to e.g. an auth or
+// validation error that will just fail again.
+func isTransientUploadErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if uerr, ok := err.(*url.Error); ok {
+		return isTransientUploadErr(uerr.Err)
+	}
+	if nerr, ok := err.(net.Error); ok {
+		return nerr.Temporary() || nerr.Timeout()
+	}
+
+	return false
+}
+
+// chunkProgressReader wraps the archive reader for a single upload
+// attempt, invoking progress every time a full uploadChunkSize worth of
+// bytes has been read, plus once more at EOF so a trailing partial
+// chunk - and archives smaller than a single chunk - are still
+// reported. Without that flush, the common case of a slug well under
+// uploadChunkSize would never print any progress at all.
+type chunkProgressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	inChunk  int64
+	progress func(sent, total int64)
+}
+
+func (c *chunkProgressReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.sent += int64(n)
+		c.inChunk += int64(n)
+
+		for c.inChunk >= uploadChunkSize {
+			c.inChunk -= uploadChunkSize
+			if c.progress != nil {
+				c.progress(c.sent, c.total)
+			}
+		}
+	}
+
+	if err == io.EOF && c.inChunk > 0 {
+		c.inChunk = 0
+		if c.progress != nil {
+			c.progress(c.sent, c.total)
+		}
+	}
+
+	return n, err
+}
+
+// chunkBackoff returns the delay before retry attempt n (1-indexed),
+// starting at 500ms and doubling up to a 30s cap.
+func chunkBackoff(attempt int) time.Duration {
+	d := time.Duration(float64(500*time.Millisecond) * math.Pow(2, float64(attempt-1)))
+	if d > 30*time.Second {
+		d = 30 * time.Second
+	}
+
+	return d
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestChunkBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 500 * time.Millisecond},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{4, 4 * time.Second},
+		{7, 30 * time.Second}, // would be 32s uncapped, clamped to 30s
+	}
+
+	for _, tc := range cases {
+		got := chunkBackoff(tc.attempt)
+		if got != tc.want {
+			t.Errorf("chunkBackoff(%d) = %s, want %s", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+type fakeNetError struct {
+	timeout   bool
+	temporary bool
+}
+
+func (e *fakeNetError) Error() string   { return "fake net error" }
+func (e *fakeNetError) Timeout() bool   { return e.timeout }
+func (e *fakeNetError) Temporary() bool { return e.temporary }
+
+func TestIsTransientUploadErr(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("bad token"), false},
+		{"timeout net.Error", &fakeNetError{timeout: true}, true},
+		{"temporary net.Error", &fakeNetError{temporary: true}, true},
+		{"non-transient net.Error", &fakeNetError{}, false},
+		{
+			"wrapped in url.Error",
+			&url.Error{Op: "Post", URL: "https://example.com", Err: &fakeNetError{timeout: true}},
+			true,
+		},
+		{
+			"wrapped non-transient",
+			&url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("403")},
+			false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransientUploadErr(tc.err); got != tc.want {
+				t.Errorf("isTransientUploadErr(%v) = %t, want %t", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+var _ net.Error = (*fakeNetError)(nil)
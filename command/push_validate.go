@@ -0,0 +1,217 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/atlas-go/archive"
+	"github.com/mitchellh/packer/packer"
+)
+
+// validateBaseDir fails if path, the resolved directory that will be
+// archived, escapes templateDir via a relative "push.base_dir" like
+// "../../etc". An absolute base_dir is left alone: it's explicitly
+// supported and the user has already opted out of anything relative to
+// the template.
+func validateBaseDir(templateDir, baseDir, path string) error {
+	if baseDir == "" || filepath.IsAbs(baseDir) {
+		return nil
+	}
+
+	// path was already made absolute by the caller; templateDir may
+	// still be relative (e.g. "." for a template in the current
+	// directory), and filepath.Rel errors if only one side is absolute.
+	absTemplateDir, err := filepath.Abs(templateDir)
+	if err != nil {
+		return fmt.Errorf("validating push.base_dir: %s", err)
+	}
+
+	rel, err := filepath.Rel(absTemplateDir, path)
+	if err != nil {
+		return fmt.Errorf("validating push.base_dir: %s", err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf(
+			"push.base_dir %q escapes the template directory", baseDir)
+	}
+
+	return nil
+}
+
+// validatePushPatterns fails if any include or exclude pattern in opts
+// doesn't match at least one file under path, which usually means the
+// pattern has a typo and the push would silently contain the wrong
+// files.
+//
+// This is skipped entirely when opts.VCS is set: the real archive's
+// file list then comes from the template's version control system
+// rather than this directory walk, so a pattern that only matches
+// VCS-tracked files (or is ignored on disk, or relies on nested globs
+// filepath.Match won't cross "/" on) would be wrongly rejected here
+// even though the real push is fine.
+func validatePushPatterns(path string, opts *archive.ArchiveOpts) error {
+	if opts.VCS {
+		return nil
+	}
+	if len(opts.Include) == 0 && len(opts.Exclude) == 0 {
+		return nil
+	}
+
+	matchedInclude := make(map[string]bool)
+	matchedExclude := make(map[string]bool)
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		for _, pattern := range opts.Include {
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				matchedInclude[pattern] = true
+			}
+		}
+		for _, pattern := range opts.Exclude {
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				matchedExclude[pattern] = true
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("validating push include/exclude: %s", err)
+	}
+
+	for _, pattern := range opts.Include {
+		if !matchedInclude[pattern] {
+			return fmt.Errorf("push.include pattern %q does not match any files", pattern)
+		}
+	}
+	for _, pattern := range opts.Exclude {
+		if !matchedExclude[pattern] {
+			return fmt.Errorf("push.exclude pattern %q does not match any files", pattern)
+		}
+	}
+
+	return nil
+}
+
+// validateMaxSize fails if size exceeds max, the configured
+// "push.max_size". max of zero means no limit.
+func validateMaxSize(size, max int64) error {
+	if max > 0 && size > max {
+		return fmt.Errorf(
+			"archive is %d bytes, which exceeds push.max_size of %d bytes", size, max)
+	}
+
+	return nil
+}
+
+// manifestFile is a single entry in a dry-run manifest: the name it
+// will have within the archive, and the real filesystem path its bytes
+// come from, which aren't always the same thing (an opts.Extra entry
+// like archiveTemplateEntry is stored in the archive under a fixed
+// name but its contents come from wherever the template file actually
+// lives).
+type manifestFile struct {
+	Name       string
+	SourcePath string
+}
+
+// manifestFiles returns the list of files that would end up in the
+// archive given opts. It mirrors the include/exclude semantics used at
+// archive time closely enough to produce an accurate dry-run manifest
+// without requiring the archive to already exist.
+//
+// This is a plain filesystem walk, not the VCS-aware file list
+// archive.CreateArchive uses when opts.VCS is set (which asks the
+// template's version control system, e.g. "git ls-files", for the file
+// list instead of walking the directory). Callers should treat the
+// manifest as a best-effort approximation in that case; see the
+// "-dry-run" warning in PushCommand.Run.
+func manifestFiles(path string, opts *archive.ArchiveOpts) ([]manifestFile, error) {
+	// Entries in opts.Extra are always added to the archive regardless
+	// of Include/Exclude (e.g. archiveTemplateEntry, the template file
+	// itself), so the manifest should list them too. The map value is
+	// the entry's real source path, which is what we need to stat -
+	// the map key is just the name it's stored under in the archive.
+	files := make([]manifestFile, 0, len(opts.Extra))
+	for name, source := range opts.Extra {
+		files = append(files, manifestFile{Name: name, SourcePath: source})
+	}
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		if len(opts.Include) > 0 && !matchesAny(opts.Include, rel) {
+			return nil
+		}
+		if matchesAny(opts.Exclude, rel) {
+			return nil
+		}
+
+		files = append(files, manifestFile{Name: rel, SourcePath: p})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printManifest prints what a real push would upload: the files that
+// would be archived, their total size, each build's artifact flag, and
+// the upload metadata. Nothing here contacts the backend.
+func (c *PushCommand) printManifest(
+	tpl *packer.Template, path string, opts *packer.PushUploadOpts, files []manifestFile) {
+	var total int64
+	for _, f := range files {
+		if info, err := os.Stat(f.SourcePath); err == nil {
+			total += info.Size()
+		}
+	}
+
+	c.Ui.Output(fmt.Sprintf("Dry run: would push '%s' (%d files, ~%d bytes)", tpl.Push.Name, len(files), total))
+	for _, f := range files {
+		c.Ui.Output(fmt.Sprintf("  %s", f.Name))
+	}
+
+	c.Ui.Output("")
+	c.Ui.Output("Builds:")
+	for name, info := range opts.Builds {
+		c.Ui.Output(fmt.Sprintf("  %s (%s) artifact=%t", name, info.Type, info.Artifact))
+	}
+
+	if len(opts.Metadata) > 0 {
+		c.Ui.Output("")
+		c.Ui.Output("Metadata:")
+		for k, v := range opts.Metadata {
+			c.Ui.Output(fmt.Sprintf("  %s=%v", k, v))
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/atlas-go/archive"
+)
+
+func TestValidateBaseDir(t *testing.T) {
+	cases := []struct {
+		name        string
+		templateDir string
+		baseDir     string
+		path        string
+		expectErr   bool
+	}{
+		{"no base_dir", "/tpl", "", "/tpl", false},
+		{"absolute base_dir always allowed", "/tpl", "/anywhere", "/anywhere", false},
+		{"relative base_dir within template dir", "/tpl", "sub", "/tpl/sub", false},
+		{"relative base_dir escaping template dir", "/tpl", "../../etc", "/etc", true},
+		{"relative templateDir against absolute path", ".", "sub", filepath.Join(mustAbs(t, "."), "sub"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateBaseDir(tc.templateDir, tc.baseDir, tc.path)
+			if tc.expectErr && err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatalf("filepath.Abs(%q): %s", path, err)
+	}
+	return abs
+}
+
+func TestValidateMaxSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		size      int64
+		max       int64
+		expectErr bool
+	}{
+		{"no limit", 1 << 30, 0, false},
+		{"under limit", 100, 200, false},
+		{"at limit", 200, 200, false},
+		{"over limit", 201, 200, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMaxSize(tc.size, tc.max)
+			if tc.expectErr && err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestManifestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-push-manifest-")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{"main.json", "scripts/setup.sh", "scripts/cleanup.sh", "README.md"}
+	for _, name := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll: %s", err)
+		}
+		if err := ioutil.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+
+	t.Run("no patterns includes everything plus extra", func(t *testing.T) {
+		extraSource := filepath.Join(dir, "main.json")
+		opts := &archive.ArchiveOpts{
+			Extra: map[string]string{archiveTemplateEntry: extraSource},
+		}
+
+		got, err := manifestFiles(dir, opts)
+		if err != nil {
+			t.Fatalf("manifestFiles: %s", err)
+		}
+
+		names := manifestFileNames(got)
+		assertContains(t, names, archiveTemplateEntry)
+		assertContains(t, names, "main.json")
+		assertContains(t, names, "scripts/setup.sh")
+		assertContains(t, names, "README.md")
+
+		for _, f := range got {
+			if f.Name == archiveTemplateEntry && f.SourcePath != extraSource {
+				t.Errorf("extra entry source path = %q, want %q", f.SourcePath, extraSource)
+			}
+		}
+	})
+
+	t.Run("include restricts to matching files", func(t *testing.T) {
+		opts := &archive.ArchiveOpts{Include: []string{"*.json"}}
+
+		got, err := manifestFiles(dir, opts)
+		if err != nil {
+			t.Fatalf("manifestFiles: %s", err)
+		}
+
+		names := manifestFileNames(got)
+		assertContains(t, names, "main.json")
+		assertNotContains(t, names, "README.md")
+		assertNotContains(t, names, "scripts/setup.sh")
+	})
+
+	t.Run("exclude removes matching files", func(t *testing.T) {
+		opts := &archive.ArchiveOpts{Exclude: []string{"*.md"}}
+
+		got, err := manifestFiles(dir, opts)
+		if err != nil {
+			t.Fatalf("manifestFiles: %s", err)
+		}
+
+		names := manifestFileNames(got)
+		assertContains(t, names, "main.json")
+		assertNotContains(t, names, "README.md")
+	})
+}
+
+func manifestFileNames(files []manifestFile) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func assertContains(t *testing.T, haystack []string, want string) {
+	t.Helper()
+	for _, v := range haystack {
+		if v == want {
+			return
+		}
+	}
+	t.Errorf("expected %v to contain %q", haystack, want)
+}
+
+func assertNotContains(t *testing.T, haystack []string, notWant string) {
+	t.Helper()
+	for _, v := range haystack {
+		if v == notWant {
+			t.Errorf("expected %v to not contain %q", haystack, notWant)
+			return
+		}
+	}
+}
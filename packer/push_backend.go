@@ -0,0 +1,67 @@
+package packer
+
+import "io"
+
+// PushBackend is implemented by anything that can accept an uploaded
+// template archive produced by "packer push". The built-in "atlas"
+// backend uploads to a Packer build service; other backends let users
+// without Atlas access publish slugs to their own infrastructure.
+type PushBackend interface {
+	// Upload sends the archive, which is exactly size bytes long, to the
+	// backend. It returns a channel that is closed when the upload
+	// completes successfully, and a channel that receives an error if the
+	// upload fails. At most one value will ever be sent across either
+	// channel.
+	Upload(archive io.Reader, size int64, opts *PushUploadOpts) (<-chan struct{}, <-chan error, error)
+}
+
+// PushUploadOpts carries the information a PushBackend needs in order to
+// identify and annotate an upload. It is populated from the template's
+// "push" section and from command-line flags.
+type PushUploadOpts struct {
+	URL      string
+	Token    string
+	Slug     string
+	Builds   map[string]*PushUploadBuildInfo
+	Metadata map[string]interface{}
+
+	// Progress, if non-nil, is called as each chunk of the archive is
+	// read for sending so the command can report upload progress to
+	// the user. It is not a confirmation that the chunk was accepted.
+	Progress func(sent, total int64)
+}
+
+// PushUploadBuildInfo describes a single build within the template that
+// is being pushed, for backends that report per-build information.
+type PushUploadBuildInfo struct {
+	Type     string
+	Artifact bool
+}
+
+// pushBackends is the registry of known PushBackend constructors, keyed
+// by the "type" value used in a template's push section.
+var pushBackends = make(map[string]func() PushBackend)
+
+// RegisterPushBackend makes a PushBackend constructor available under
+// the given type name. It is expected to be called from init() in the
+// package implementing the backend. It panics if the same type is
+// registered more than once, mirroring the pattern used elsewhere in
+// this package for registering plugins.
+func RegisterPushBackend(pushType string, f func() PushBackend) {
+	if _, ok := pushBackends[pushType]; ok {
+		panic("push backend already registered: " + pushType)
+	}
+
+	pushBackends[pushType] = f
+}
+
+// PushBackendFor returns a new instance of the PushBackend registered
+// under pushType, or false if no backend is registered under that name.
+func PushBackendFor(pushType string) (PushBackend, bool) {
+	f, ok := pushBackends[pushType]
+	if !ok {
+		return nil, false
+	}
+
+	return f(), true
+}
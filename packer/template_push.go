@@ -0,0 +1,29 @@
+package packer
+
+// TemplatePush is the configuration structure for the "push" section
+// within a template. It configures whether and how "packer push" sends
+// a template and its supporting files to a build service.
+type TemplatePush struct {
+	Name    string
+	Address string
+	BaseDir string `mapstructure:"base_dir"`
+	Include []string
+	Exclude []string
+	Token   string
+	VCS     bool
+
+	// Type selects the PushBackend used to upload the archive produced
+	// from this template. It defaults to "atlas" so that existing
+	// templates continue to push to Atlas without any changes.
+	Type string
+
+	// Output, if set, makes "packer push" write the archive to this
+	// path on disk instead of uploading it anywhere. The -output flag
+	// takes precedence over this.
+	Output string
+
+	// MaxSize, if non-zero, is the largest archive in bytes that
+	// "packer push" will send anywhere. Archives over this size fail
+	// validation instead of being silently uploaded.
+	MaxSize int64 `mapstructure:"max_size"`
+}